@@ -5,11 +5,14 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"math"
+	"os"
 	"time"
 
 	"github.com/Shinonome517/tcp-quic-bench/internal/client"
 	"github.com/Shinonome517/tcp-quic-bench/internal/data"
+	"github.com/Shinonome517/tcp-quic-bench/internal/netem"
+	"github.com/Shinonome517/tcp-quic-bench/internal/quicx"
+	"github.com/Shinonome517/tcp-quic-bench/internal/report"
 	"github.com/Shinonome517/tcp-quic-bench/internal/server"
 )
 
@@ -18,68 +21,263 @@ const (
 	measurementRuns = 10 // 計測実行回数
 )
 
+// benchmarkConfig は、コマンドラインフラグから読み取ったベンチマークの実行設定をまとめたものである。
+type benchmarkConfig struct {
+	proto       string
+	addr        string
+	zeroRTT     bool
+	workload    string // bulk, rr or streams
+	rrCount     int
+	numStreams  int
+	streamBytes int64
+	netemCfg    *netem.Config // nilの場合、ネットワークエミュレーションは無効
+	cc          string        // 輻輳制御アルゴリズム名（cubic, reno, bbr）。空文字の場合はデフォルトのまま
+	ccLogFile   string        // 非空の場合、QUICの輻輳制御メトリクスをこのパスへCSVとして書き出す
+	output      string        // text, json or csv
+	outputFile  string        // 非空の場合、結果をstdoutの代わりにこのパスへ書き出す
+	payload     string        // ペイロードの生成方法: random, zero or file
+	payloadFile string        // payload=fileの場合にmmapするファイルのパス
+	payloadSize int64         // bulk/server側で送信するペイロードサイズ（payload=fileの場合は無視される）
+}
+
 // main はアプリケーションのエントリーポイントである。
 func main() {
 	// コマンドラインフラグを定義する。
 	mode := flag.String("mode", "server", "server or client")
-	proto := flag.String("proto", "quic", "tcp or quic")
+	proto := flag.String("proto", "quic", "tcp, quic or http3")
 	addr := flag.String("addr", "127.0.0.1:4242", "address and port")
+	zeroRTT := flag.Bool("zerortt", false, "benchmark 0-RTT/TCP Fast Open session resumption (tcp and quic only)")
+	workload := flag.String("workload", "bulk", "bulk, rr or streams")
+	rrCount := flag.Int("rr-count", 1000, "number of request/response round trips for the rr workload")
+	numStreams := flag.Int("streams", 4, "number of concurrent streams/connections for the streams workload")
+	streamBytes := flag.Int64("stream-bytes", 128<<20, "bytes to transfer per stream for the streams workload")
+	rtt := flag.Duration("rtt", 0, "emulated round-trip time, e.g. 50ms (tcp and quic bulk workload only)")
+	jitter := flag.Duration("jitter", 0, "emulated jitter added on top of rtt/2 per packet")
+	lossPct := flag.Float64("loss", 0, "emulated packet loss percentage, 0-100 (quic only)")
+	reorderPct := flag.Float64("reorder", 0, "emulated packet reordering percentage, 0-100 (quic only)")
+	bwMbps := flag.Float64("bw", 0, "emulated bandwidth cap in Mbps, 0 = unlimited")
+	cc := flag.String("cc", "", "congestion control algorithm: cubic, reno or bbr, set via TCP_CONGESTION for tcp; for quic it only labels the -cc-log CSV (bulk workload only; empty = OS/quic-go default)")
+	ccLogFile := flag.String("cc-log", "", "if set and -proto=quic, write per-sample cwnd/RTT/bytes-in-flight CSV to this path (bulk workload only)")
+	output := flag.String("output", "text", "result output format: text, json or csv")
+	outputFile := flag.String("output-file", "", "if set, write results to this path instead of stdout")
+	size := flag.Int64("size", 1<<30, "payload size in bytes for the bulk server (ignored for -payload=file; the streams server uses -stream-bytes)")
+	payload := flag.String("payload", "random", "payload generator: random, zero or file")
+	payloadFile := flag.String("payload-file", "", "path to mmap as the payload when -payload=file")
 	flag.Parse()
 
+	var netemCfg *netem.Config
+	if nc := (netem.Config{
+		RTT:          *rtt,
+		Jitter:       *jitter,
+		LossPct:      *lossPct / 100,
+		ReorderPct:   *reorderPct / 100,
+		BandwidthBps: int64(*bwMbps * 1e6),
+	}); nc.Enabled() {
+		netemCfg = &nc
+	}
+
+	cfg := benchmarkConfig{
+		proto:       *proto,
+		addr:        *addr,
+		zeroRTT:     *zeroRTT,
+		workload:    *workload,
+		rrCount:     *rrCount,
+		numStreams:  *numStreams,
+		streamBytes: *streamBytes,
+		netemCfg:    netemCfg,
+		cc:          *cc,
+		ccLogFile:   *ccLogFile,
+		output:      *output,
+		outputFile:  *outputFile,
+		payload:     *payload,
+		payloadFile: *payloadFile,
+		payloadSize: *size,
+	}
+
 	// モードを確認し、対応するロジックを実行する。
 	switch *mode {
 	case "server":
-		runServer(*proto, *addr)
+		runServer(cfg)
 	case "client":
-		runClient(*proto, *addr)
+		runClient(cfg)
 	default:
 		log.Fatalf("Unknown mode: %s. Please use 'server' or 'client'.", *mode)
 	}
 }
 
-func runServer(proto, addr string) {
-	log.Println("Generating 1GB of random data...")
-	benchmarkData, err := data.Generate()
+func runServer(cfg benchmarkConfig) {
+	switch cfg.workload {
+	case "rr":
+		runRRServer(cfg)
+	case "streams":
+		runStreamsServer(cfg)
+	default:
+		runBulkServer(cfg)
+	}
+}
+
+// newPayloadSource は、cfgの-payload/-payload-file/-sizeフラグから送信用ペイロードの
+// data.Sourceを構築する。
+func newPayloadSource(cfg benchmarkConfig) (data.Source, error) {
+	switch cfg.payload {
+	case "random", "":
+		return data.NewRandomSource(cfg.payloadSize), nil
+	case "zero":
+		return data.NewZeroSource(cfg.payloadSize), nil
+	case "file":
+		if cfg.payloadFile == "" {
+			return nil, fmt.Errorf("-payload=file requires -payload-file")
+		}
+		return data.NewFileSource(cfg.payloadFile)
+	default:
+		return nil, fmt.Errorf("unknown payload type: %s (must be random, zero or file)", cfg.payload)
+	}
+}
+
+func runBulkServer(cfg benchmarkConfig) {
+	source, err := newPayloadSource(cfg)
 	if err != nil {
-		log.Fatalf("Failed to generate data: %v", err)
+		log.Fatalf("Failed to set up payload source: %v", err)
 	}
-	log.Println("Data generation complete.")
+	log.Printf("Serving %d bytes per connection (-payload=%s).", source.Size(), cfg.payload)
+
+	opts := server.RunOptions{ZeroRTT: cfg.zeroRTT, NetemCfg: cfg.netemCfg, CC: cfg.cc}
 
-	switch proto {
+	switch cfg.proto {
 	case "tcp":
-		log.Printf("Starting TCP server on %s...", addr)
-		if err := server.RunTCPServer(addr, benchmarkData); err != nil {
+		log.Printf("Starting TCP server on %s...", cfg.addr)
+		if err := server.RunTCPServer(cfg.addr, source, opts); err != nil {
 			log.Fatalf("TCP server failed: %v", err)
 		}
 	case "quic":
-		log.Printf("Starting QUIC server on %s...", addr)
-		if err := server.RunQUICServer(addr, benchmarkData); err != nil {
+		log.Printf("Starting QUIC server on %s...", cfg.addr)
+		if err := server.RunQUICServer(cfg.addr, source, opts); err != nil {
 			log.Fatalf("QUIC server failed: %v", err)
 		}
+	case "http3":
+		log.Printf("Starting HTTP/3 server on %s...", cfg.addr)
+		if err := server.RunHTTP3Server(cfg.addr, source); err != nil {
+			log.Fatalf("HTTP/3 server failed: %v", err)
+		}
+	default:
+		log.Fatalf("Unknown protocol: %s", cfg.proto)
+	}
+}
+
+// runRRServer は、リクエスト/レスポンス（rr）ワークロード用のエコーサーバーを起動する。
+func runRRServer(cfg benchmarkConfig) {
+	switch cfg.proto {
+	case "tcp":
+		log.Printf("Starting TCP rr server on %s...", cfg.addr)
+		if err := server.RunTCPServerRR(cfg.addr); err != nil {
+			log.Fatalf("TCP rr server failed: %v", err)
+		}
+	case "quic":
+		log.Printf("Starting QUIC rr server on %s...", cfg.addr)
+		if err := server.RunQUICServerRR(cfg.addr); err != nil {
+			log.Fatalf("QUIC rr server failed: %v", err)
+		}
+	default:
+		log.Fatalf("Unsupported protocol for rr workload: %s", cfg.proto)
+	}
+}
+
+// runStreamsServer は、streamsワークロード用のサーバーを起動する。TCPは通常のRunTCPServerを
+// 再利用し（クライアント側がnumStreams本の並行接続を張ることで多重化の代わりとする）、QUICは
+// 1接続上にnumStreams本のストリームを開くRunQUICServerStreamsを使う。
+func runStreamsServer(cfg benchmarkConfig) {
+	streamCfg := cfg
+	streamCfg.payloadSize = cfg.streamBytes
+	rawSource, err := newPayloadSource(streamCfg)
+	if err != nil {
+		log.Fatalf("Failed to set up payload source: %v", err)
+	}
+	// -payload=fileの場合、ファイルサイズは-stream-bytesと独立に決まるため、クライアントが
+	// 期待する長さに明示的に揃える（randomとzeroは既にcfg.streamBytesちょうどのサイズである）。
+	source, err := data.WithSize(rawSource, cfg.streamBytes)
+	if err != nil {
+		log.Fatalf("Failed to set up payload source: %v", err)
+	}
+	log.Printf("Serving %d bytes per stream (-payload=%s).", source.Size(), cfg.payload)
+
+	switch cfg.proto {
+	case "tcp":
+		log.Printf("Starting TCP streams server on %s...", cfg.addr)
+		opts := server.RunOptions{ZeroRTT: cfg.zeroRTT, NetemCfg: cfg.netemCfg, CC: cfg.cc}
+		if err := server.RunTCPServer(cfg.addr, source, opts); err != nil {
+			log.Fatalf("TCP server failed: %v", err)
+		}
+	case "quic":
+		log.Printf("Starting QUIC streams server on %s...", cfg.addr)
+		if err := server.RunQUICServerStreams(cfg.addr, source, cfg.numStreams); err != nil {
+			log.Fatalf("QUIC streams server failed: %v", err)
+		}
+	default:
+		log.Fatalf("Unsupported protocol for streams workload: %s", cfg.proto)
+	}
+}
+
+// newReporter は、cfg.outputに対応するreport.Reporterを生成する。cfg.outputFileが
+// 非空の場合はそのパスへ書き出し、空の場合は標準出力へ書き出す。呼び出し元は返された
+// closeに対してdeferすること（outputFileを指定しなかった場合はno-opである）。
+func newReporter(cfg benchmarkConfig) (rep report.Reporter, closeFn func(), err error) {
+	w := os.Stdout
+	closeFn = func() {}
+	if cfg.outputFile != "" {
+		f, err := os.Create(cfg.outputFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		w = f
+		closeFn = func() { f.Close() }
+	}
+
+	rep, err = report.NewReporter(cfg.output, w)
+	if err != nil {
+		closeFn()
+		return nil, nil, err
+	}
+	return rep, closeFn, nil
+}
+
+func runClient(cfg benchmarkConfig) {
+	switch cfg.workload {
+	case "rr":
+		runRRClient(cfg)
+	case "streams":
+		runStreamsClient(cfg)
 	default:
-		log.Fatalf("Unknown protocol: %s", proto)
+		runBulkClient(cfg)
 	}
 }
 
-func runClient(proto, addr string) {
+func runBulkClient(cfg benchmarkConfig) {
 	var totalBytes int64
 	handshakeDurations := make([]time.Duration, 0, measurementRuns)
+	zeroRTTDurations := make([]time.Duration, 0, measurementRuns)
 	dataTransferDurations := make([]time.Duration, 0, measurementRuns)
 
-	log.Printf("Starting %s client with %d warmup runs and %d measurement runs...", proto, warmupRuns, measurementRuns)
+	opts := client.RunOptions{ZeroRTT: cfg.zeroRTT, NetemCfg: cfg.netemCfg, CC: cfg.cc}
+	if cfg.proto == "quic" && cfg.ccLogFile != "" {
+		opts.CCLogger = quicx.NewCCLogger(cfg.cc)
+	}
+
+	log.Printf("Starting %s client with %d warmup runs and %d measurement runs...", cfg.proto, warmupRuns, measurementRuns)
 
 	for i := 0; i < warmupRuns+measurementRuns; i++ {
-		var hsDur, dtDur time.Duration
+		var hsDur, zrDur, dtDur time.Duration
 		var err error
 		var bytes int64
 
-		switch proto {
+		switch cfg.proto {
 		case "tcp":
-			bytes, hsDur, dtDur, err = client.RunTCPClient(addr)
+			bytes, hsDur, zrDur, dtDur, err = client.RunTCPClient(cfg.addr, opts)
 		case "quic":
-			bytes, hsDur, dtDur, err = client.RunQUICClient(addr)
+			bytes, hsDur, zrDur, dtDur, err = client.RunQUICClient(cfg.addr, opts)
+		case "http3":
+			bytes, hsDur, dtDur, err = client.RunHTTP3Client(cfg.addr)
 		default:
-			log.Fatalf("Unknown protocol: %s", proto)
+			log.Fatalf("Unknown protocol: %s", cfg.proto)
 		}
 
 		if err != nil {
@@ -92,86 +290,97 @@ func runClient(proto, addr string) {
 
 		if i >= warmupRuns {
 			handshakeDurations = append(handshakeDurations, hsDur)
+			if zrDur > 0 { // 再開が成立した実行のみ0-RTTメトリクスに計上する
+				zeroRTTDurations = append(zeroRTTDurations, zrDur)
+			}
 			dataTransferDurations = append(dataTransferDurations, dtDur)
 		}
 		time.Sleep(100 * time.Millisecond) // 各実行間の短い待機
 	}
 
-	PrintResults(totalBytes, handshakeDurations, dataTransferDurations)
-}
-
-// calculateStatistics は time.Duration のスライスから平均値と標準偏差を計算する。
-func calculateStatistics(durations []time.Duration) (mean, stdDev time.Duration) {
-	if len(durations) == 0 {
-		return 0, 0
+	rep, closeReport, err := newReporter(cfg)
+	if err != nil {
+		log.Fatalf("failed to set up output: %v", err)
 	}
-
-	var sum float64
-	for _, d := range durations {
-		sum += d.Seconds()
+	defer closeReport()
+	if err := rep.ReportBulk(report.NewBulkReport(totalBytes, handshakeDurations, zeroRTTDurations, dataTransferDurations)); err != nil {
+		log.Fatalf("failed to write report: %v", err)
 	}
-	meanSeconds := sum / float64(len(durations))
 
-	var sumSqDiff float64
-	for _, d := range durations {
-		diff := d.Seconds() - meanSeconds
-		sumSqDiff += diff * diff
+	if opts.CCLogger != nil {
+		writeCCLog(cfg.ccLogFile, opts.CCLogger)
 	}
-
-	variance := sumSqDiff / float64(len(durations))
-	stdDevSeconds := math.Sqrt(variance)
-
-	mean = time.Duration(meanSeconds * float64(time.Second))
-	stdDev = time.Duration(stdDevSeconds * float64(time.Second))
-	return
 }
 
-// PrintResults はベンチマーク結果を計算して表示する。
-// 総バイト数と時間からスループット（Gbps）を算出し、整形して標準出力に表示する。
-func PrintResults(totalBytes int64, handshakeDurations, dataTransferDurations []time.Duration) {
-	handshakeMean, handshakeStdDev := calculateStatistics(handshakeDurations)
-	dataTransferMean, dataTransferStdDev := calculateStatistics(dataTransferDurations)
-
-	// 各実行の合計時間を計算し、その統計情報を取得
-	var totalDurations []time.Duration
-	for i := 0; i < len(handshakeDurations); i++ {
-		totalDurations = append(totalDurations, handshakeDurations[i]+dataTransferDurations[i])
-	}
-	totalMean, totalStdDev := calculateStatistics(totalDurations)
-
-	// 平均スループットの計算
-	// 各実行のスループットを計算し、その平均を取る
-	var throughputs []float64
-	for _, totalDur := range totalDurations {
-		totalDurSeconds := totalDur.Seconds()
-		if totalDurSeconds > 0 {
-			throughputs = append(throughputs, (float64(totalBytes)*8)/(totalDurSeconds*1e9))
-		}
+// writeCCLog は、蓄積された輻輳制御メトリクスをpathへCSVとして書き出す。
+func writeCCLog(path string, logger *quicx.CCLogger) {
+	f, err := os.Create(path)
+	if err != nil {
+		log.Printf("failed to create cc-log file %s: %v", path, err)
+		return
 	}
+	defer f.Close()
 
-	var sumThroughput float64
-	for _, t := range throughputs {
-		sumThroughput += t
+	if err := logger.WriteCSV(f); err != nil {
+		log.Printf("failed to write cc-log CSV to %s: %v", path, err)
+		return
 	}
-	meanThroughput := sumThroughput / float64(len(throughputs))
+	log.Printf("Congestion control metrics written to %s", path)
+}
 
-	fmt.Println("\n--- Benchmark Results ---")
-	fmt.Printf("Total bytes received per run: %d bytes\n", totalBytes)
-	fmt.Printf("Number of measurement runs: %d\n", len(handshakeDurations))
-	fmt.Println("-------------------------")
+// runRRClient はrr（リクエスト/レスポンス）ワークロードを実行し、往復レイテンシの
+// パーセンタイルを表示する。
+func runRRClient(cfg benchmarkConfig) {
+	log.Printf("Starting %s rr client with %d round trips...", cfg.proto, cfg.rrCount)
 
-	fmt.Printf("Handshake time (Mean):      %.4f s\n", handshakeMean.Seconds())
-	fmt.Printf("Handshake time (StdDev):    %.4f s\n", handshakeStdDev.Seconds())
-	fmt.Println("-------------------------")
+	var stats client.RRStats
+	var err error
+	switch cfg.proto {
+	case "tcp":
+		stats, err = client.RunTCPClientRR(cfg.addr, cfg.rrCount)
+	case "quic":
+		stats, err = client.RunQUICClientRR(cfg.addr, cfg.rrCount)
+	default:
+		log.Fatalf("Unsupported protocol for rr workload: %s", cfg.proto)
+	}
+	if err != nil {
+		log.Fatalf("Client run failed: %v", err)
+	}
+
+	rep, closeReport, err := newReporter(cfg)
+	if err != nil {
+		log.Fatalf("failed to set up output: %v", err)
+	}
+	defer closeReport()
+	if err := rep.ReportRR(report.NewRRReport(stats)); err != nil {
+		log.Fatalf("failed to write report: %v", err)
+	}
+}
 
-	fmt.Printf("Data transfer time (Mean):  %.4f s\n", dataTransferMean.Seconds())
-	fmt.Printf("Data transfer time (StdDev): %.4f s\n", dataTransferStdDev.Seconds())
-	fmt.Println("-------------------------")
+// runStreamsClient はstreamsワークロードを実行し、ストリームごとのスループットを表示する。
+func runStreamsClient(cfg benchmarkConfig) {
+	log.Printf("Starting %s streams client with %d concurrent streams of %d bytes each...", cfg.proto, cfg.numStreams, cfg.streamBytes)
 
-	fmt.Printf("Total time (Mean):          %.4f s\n", totalMean.Seconds())
-	fmt.Printf("Total time (StdDev):        %.4f s\n", totalStdDev.Seconds())
-	fmt.Println("-------------------------")
+	var stats []client.StreamStats
+	var err error
+	switch cfg.proto {
+	case "tcp":
+		stats, err = client.RunTCPClientStreams(cfg.addr, cfg.numStreams, cfg.streamBytes)
+	case "quic":
+		stats, err = client.RunQUICClientStreams(cfg.addr, cfg.numStreams, cfg.streamBytes)
+	default:
+		log.Fatalf("Unsupported protocol for streams workload: %s", cfg.proto)
+	}
+	if err != nil {
+		log.Fatalf("Client run failed: %v", err)
+	}
 
-	fmt.Printf("Throughput (Mean):          %.4f Gbps\n", meanThroughput)
-	fmt.Println("-------------------------")
+	rep, closeReport, err := newReporter(cfg)
+	if err != nil {
+		log.Fatalf("failed to set up output: %v", err)
+	}
+	defer closeReport()
+	if err := rep.ReportStreams(report.NewStreamsReport(stats)); err != nil {
+		log.Fatalf("failed to write report: %v", err)
+	}
 }
@@ -7,106 +7,213 @@ import (
 	"io"
 	"log"
 	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/Shinonome517/tcp-quic-bench/internal/netem"
+	"github.com/Shinonome517/tcp-quic-bench/internal/quicx"
+	"github.com/Shinonome517/tcp-quic-bench/internal/stats"
 	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
 	"golang.org/x/sys/unix"
 )
 
+// rrPayloadSize は rr ワークロードにおける1回のリクエスト/レスポンスあたりのペイロードサイズです。
+const rrPayloadSize = 64
+
+// tcpSessionCache と quicSessionCache は、-zerortt が有効な場合にTLSセッションチケットを
+// 保持するためのキャッシュです。最初の接続でチケットが保存され、以降の接続
+// （TCPはTLS 1.3セッション再開、QUICは0-RTT再開）で再利用されます。
+var (
+	tcpSessionCache  = tls.NewLRUClientSessionCache(1)
+	quicSessionCache = tls.NewLRUClientSessionCache(1)
+)
+
+// RunOptions は、bulkワークロードのRunTCPClient/RunQUICClientの挙動を制御する
+// 共通オプションをまとめたものである。フラグが増えるたびにこの構造体へフィールドを
+// 追加していく。
+type RunOptions struct {
+	ZeroRTT  bool
+	NetemCfg *netem.Config   // nilの場合、ネットワークエミュレーションは無効
+	CC       string          // 輻輳制御アルゴリズム名（cubic, reno, bbr）。空文字の場合はOS/quic-goのデフォルトのまま
+	CCLogger *quicx.CCLogger // 非nilの場合、QUICの輻輳制御メトリクスをCCLoggerへ記録する（QUICのみ有効）
+}
+
 // RunTCPClient はTCPサーバーに接続し、パフォーマンスを測定します。
 // 指定されたアドレスにTCP接続を試み、サーバーからのデータストリームを受信します。
+// opts.ZeroRTTがtrueの場合、TLS 1.3セッション再開とTCP Fast Open（TCP_FASTOPEN_CONNECT）を
+// 有効にして接続し、再開が成立した場合はそのハンドシェイク時間をzeroRTTDurationとして
+// 別途返します。opts.NetemCfgが非nilの場合、接続にRTT・帯域等のネットワーク劣化条件を注入します。
+// opts.CCが空文字でない場合、TCP_CONGESTIONソケットオプションで輻輳制御アルゴリズムを指定します。
 // 受信したデータは破棄され、転送にかかった時間と総バイト数を返します。
-func RunTCPClient(addr string) (int64, time.Duration, time.Duration, error) {
+func RunTCPClient(addr string, opts RunOptions) (int64, time.Duration, time.Duration, time.Duration, error) {
 	// TLS設定を作成（自己署名証明書を許容）
 	tlsConf := &tls.Config{
 		InsecureSkipVerify: true, // サーバーは自己署名証明書のため検証をスキップ
 		NextProtos:         []string{"tcp-quic-bench"},
 	}
 
+	dialer := &net.Dialer{}
+	if opts.ZeroRTT {
+		tlsConf.ClientSessionCache = tcpSessionCache
+		// SYNパケットに後続のTLSデータを相乗りさせるため、TCP Fast Openを有効化する
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			var sockErr error
+			if err := c.Control(func(fd uintptr) {
+				sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_FASTOPEN_CONNECT, 1)
+			}); err != nil {
+				return err
+			}
+			return sockErr
+		}
+	}
+
 	log.Println("Connecting via TCP...")
 
 	// TCP接続を確立
 	handshakeStartTime := time.Now()
-	dialer := &net.Dialer{}
 	rawConn, err := dialer.Dial("tcp", addr)
 	if err != nil {
-		return 0, 0, 0, fmt.Errorf("failed to dial TCP: %w", err)
+		return 0, 0, 0, 0, fmt.Errorf("failed to dial TCP: %w", err)
 	}
 
 	// TCP接続のファイルディスクリプタを取得し、MSSを設定
 	tcpConn, ok := rawConn.(*net.TCPConn)
 	if !ok {
-		return 0, 0, 0, fmt.Errorf("failed to get TCP connection")
+		return 0, 0, 0, 0, fmt.Errorf("failed to get TCP connection")
 	}
 	syscallConn, err := tcpConn.SyscallConn()
 	if err != nil {
-		return 0, 0, 0, fmt.Errorf("failed to get syscall connection: %w", err)
+		return 0, 0, 0, 0, fmt.Errorf("failed to get syscall connection: %w", err)
 	}
 	err = syscallConn.Control(func(fd uintptr) {
 		err := unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_MAXSEG, 1240) // MSSを1240に設定
 		if err != nil {
 			log.Printf("failed to set TCP_MAXSEG: %v", err)
 		}
+		if opts.CC != "" {
+			if err := unix.SetsockoptString(int(fd), unix.IPPROTO_TCP, unix.TCP_CONGESTION, opts.CC); err != nil {
+				log.Printf("failed to set TCP_CONGESTION to %q: %v", opts.CC, err)
+			}
+		}
 	})
 	if err != nil {
-		return 0, 0, 0, fmt.Errorf("failed to control raw connection: %w", err)
+		return 0, 0, 0, 0, fmt.Errorf("failed to control raw connection: %w", err)
+	}
+
+	// netemCfgが指定されている場合、TLSハンドシェイクを含む以降の通信すべてに
+	// ネットワーク劣化条件を注入する
+	var transportConn net.Conn = rawConn
+	if opts.NetemCfg != nil {
+		transportConn = netem.NewConn(rawConn, *opts.NetemCfg)
 	}
 
 	// TLSハンドシェイク
-	conn := tls.Client(rawConn, tlsConf)
+	conn := tls.Client(transportConn, tlsConf)
 	if err := conn.Handshake(); err != nil {
-		return 0, 0, 0, fmt.Errorf("failed to perform TLS handshake: %w", err)
+		return 0, 0, 0, 0, fmt.Errorf("failed to perform TLS handshake: %w", err)
 	}
 	handshakeDuration := time.Since(handshakeStartTime)
 	defer conn.Close()
 
+	// セッション再開が成立した場合のみ、0-RTTハンドシェイク時間として記録する
+	var zeroRTTDuration time.Duration
+	if opts.ZeroRTT && conn.ConnectionState().DidResume {
+		zeroRTTDuration = handshakeDuration
+	}
+
 	log.Println("TCP connection established. Receiving data...")
 
 	// サーバーからのデータをすべて受信し、io.Discardで破棄する
 	dataTransferStartTime := time.Now()
 	bytesCopied, err := io.Copy(io.Discard, conn)
 	if err != nil {
-		return 0, 0, 0, fmt.Errorf("failed to receive data over TCP: %w", err)
+		return 0, 0, 0, 0, fmt.Errorf("failed to receive data over TCP: %w", err)
 	}
 	dataTransferDuration := time.Since(dataTransferStartTime)
 
 	log.Println("TCP data transfer complete.")
-	return bytesCopied, handshakeDuration, dataTransferDuration, nil
+	return bytesCopied, handshakeDuration, zeroRTTDuration, dataTransferDuration, nil
 }
 
 // RunQUICClient はQUICサーバーに接続し、パフォーマンスを測定します。
 // 自己署名証明書を許容するTLS設定でQUIC接続を試み、サーバーからのストリームを受信します。
-// 受信したデータは破棄され、転送にかかった時間と総バイト数を返します。
-func RunQUICClient(addr string) (int64, time.Duration, time.Duration, error) {
+// opts.ZeroRTTがtrueの場合、保存済みのセッションチケットを使って quic.DialAddrEarly による
+// 0-RTT再開を試み、再開が成立した場合はそのハンドシェイク時間をzeroRTTDurationとして
+// 別途返します。opts.NetemCfgが非nilの場合、quic.Transportに差し込んだnetem.PacketConn経由で
+// 接続し、RTT・帯域等のネットワーク劣化条件を注入します。opts.CCLoggerが非nilの場合、
+// quic.Config.Tracerに輻輳制御メトリクスのロガーを差し込みます（quic-goはCCアルゴリズムの
+// 選択を公開していないため、opts.CCはCSVのラベルとしてのみ使われます）。受信したデータは
+// 破棄され、転送にかかった時間と総バイト数を返します。
+func RunQUICClient(addr string, opts RunOptions) (int64, time.Duration, time.Duration, time.Duration, error) {
 	// QUIC接続のためのTLS設定
 	tlsConf := &tls.Config{
 		InsecureSkipVerify: true, // サーバーは自己署名証明書のため検証をスキップ
 		NextProtos:         []string{"tcp-quic-bench"},
 	}
 
-	log.Println("Connecting via QUIC...")
-
 	// QUICの設定
 	quicConfig := &quic.Config{
 		DisablePathMTUDiscovery: true,
 		MaxIdleTimeout:          time.Minute,
 	}
+	if opts.CCLogger != nil {
+		quicConfig.Tracer = quicx.NewTracerFactory(opts.CCLogger)
+	}
+
+	var conn *quic.Conn
+	var err error
 
 	handshakeStartTime := time.Now()
-	// QUICサーバーにダイヤル
-	conn, err := quic.DialAddr(context.Background(), addr, tlsConf, quicConfig)
+	switch {
+	case opts.NetemCfg != nil:
+		udpConn, listenErr := net.ListenUDP("udp", nil)
+		if listenErr != nil {
+			return 0, 0, 0, 0, fmt.Errorf("failed to open UDP socket: %w", listenErr)
+		}
+		raddr, resolveErr := net.ResolveUDPAddr("udp", addr)
+		if resolveErr != nil {
+			return 0, 0, 0, 0, fmt.Errorf("failed to resolve QUIC server address: %w", resolveErr)
+		}
+		tr := &quic.Transport{Conn: netem.NewPacketConn(udpConn, *opts.NetemCfg)}
+		defer tr.Close() // tr.Connに設定したnetem.PacketConn（とそのdeliveryLoopゴルーチン）もここで閉じる
+		if opts.ZeroRTT {
+			tlsConf.ClientSessionCache = quicSessionCache
+			log.Println("Connecting via QUIC with 0-RTT resumption (netem enabled)...")
+			conn, err = tr.DialEarly(context.Background(), raddr, tlsConf, quicConfig)
+		} else {
+			log.Println("Connecting via QUIC (netem enabled)...")
+			conn, err = tr.Dial(context.Background(), raddr, tlsConf, quicConfig)
+		}
+	case opts.ZeroRTT:
+		tlsConf.ClientSessionCache = quicSessionCache
+		log.Println("Connecting via QUIC with 0-RTT resumption...")
+		conn, err = quic.DialAddrEarly(context.Background(), addr, tlsConf, quicConfig)
+	default:
+		log.Println("Connecting via QUIC...")
+		conn, err = quic.DialAddr(context.Background(), addr, tlsConf, quicConfig)
+	}
 	if err != nil {
-		return 0, 0, 0, fmt.Errorf("failed to connect to QUIC server: %w", err)
+		return 0, 0, 0, 0, fmt.Errorf("failed to connect to QUIC server: %w", err)
 	}
 	handshakeDuration := time.Since(handshakeStartTime)
 	defer conn.CloseWithError(0, "")
 
+	// セッション再開（0-RTT）が成立した場合のみ、別メトリクスとして記録する
+	var zeroRTTDuration time.Duration
+	if opts.ZeroRTT && conn.ConnectionState().TLS.DidResume {
+		zeroRTTDuration = handshakeDuration
+	}
+
 	log.Println("QUIC connection established. Opening stream...")
 
 	// サーバーからのストリームを受け入れる
 	stream, err := conn.AcceptStream(context.Background())
 	if err != nil {
-		return 0, 0, 0, fmt.Errorf("failed to open QUIC stream: %w", err)
+		return 0, 0, 0, 0, fmt.Errorf("failed to open QUIC stream: %w", err)
 	}
 	defer stream.Close()
 
@@ -116,10 +223,264 @@ func RunQUICClient(addr string) (int64, time.Duration, time.Duration, error) {
 	dataTransferStartTime := time.Now()
 	bytesCopied, err := io.Copy(io.Discard, stream)
 	if err != nil {
-		return 0, 0, 0, fmt.Errorf("failed to receive data over QUIC: %w", err)
+		return 0, 0, 0, 0, fmt.Errorf("failed to receive data over QUIC: %w", err)
 	}
 	dataTransferDuration := time.Since(dataTransferStartTime)
 
 	log.Println("QUIC data transfer complete.")
+	return bytesCopied, handshakeDuration, zeroRTTDuration, dataTransferDuration, nil
+}
+
+// RunHTTP3Client はHTTP/3サーバーにGETリクエストを送り、パフォーマンスを測定します。
+// handshakeDurationにはQUICハンドシェイクとレスポンスヘッダー受信までの時間（TTFB）を、
+// dataTransferDurationにはレスポンスボディの転送時間を格納して返します。
+func RunHTTP3Client(addr string) (int64, time.Duration, time.Duration, error) {
+	// QUIC接続のためのTLS設定
+	tlsConf := &tls.Config{
+		InsecureSkipVerify: true, // サーバーは自己署名証明書のため検証をスキップ
+		NextProtos:         []string{"tcp-quic-bench"},
+	}
+
+	// QUICの設定
+	quicConfig := &quic.Config{
+		DisablePathMTUDiscovery: true,
+		MaxIdleTimeout:          time.Minute,
+	}
+
+	roundTripper := &http3.Transport{
+		TLSClientConfig: tlsConf,
+		QUICConfig:      quicConfig,
+	}
+	defer roundTripper.Close()
+
+	httpClient := &http.Client{Transport: roundTripper}
+
+	log.Println("Connecting via HTTP/3...")
+
+	// ハンドシェイクとレスポンスヘッダー受信までの時間（TTFB）を計測
+	handshakeStartTime := time.Now()
+	resp, err := httpClient.Get(fmt.Sprintf("https://%s/", addr))
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to perform HTTP/3 request: %w", err)
+	}
+	handshakeDuration := time.Since(handshakeStartTime)
+	defer resp.Body.Close()
+
+	log.Println("HTTP/3 response headers received. Receiving body...")
+
+	// レスポンスボディをすべて受信し、io.Discardで破棄する
+	dataTransferStartTime := time.Now()
+	bytesCopied, err := io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to receive HTTP/3 response body: %w", err)
+	}
+	dataTransferDuration := time.Since(dataTransferStartTime)
+
+	log.Println("HTTP/3 data transfer complete.")
 	return bytesCopied, handshakeDuration, dataTransferDuration, nil
 }
+
+// RRStats はrr（リクエスト/レスポンス）ワークロードにおける往復レイテンシの統計情報を表す。
+type RRStats struct {
+	Count int
+	P50   time.Duration
+	P95   time.Duration
+	P99   time.Duration
+}
+
+// rrStatsFromLatencies は個々の往復のレイテンシからRRStatsを計算する。
+func rrStatsFromLatencies(latencies []time.Duration) RRStats {
+	sorted := make([]time.Duration, len(latencies))
+	copy(sorted, latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return RRStats{
+		Count: len(sorted),
+		P50:   stats.Percentile(sorted, 50),
+		P95:   stats.Percentile(sorted, 95),
+		P99:   stats.Percentile(sorted, 99),
+	}
+}
+
+// RunTCPClientRR はTCPサーバーとの間でrrワークロードを実行します。1本のTLS接続上でcount回の
+// 小さな往復を行い、各往復のレイテンシからp50/p95/p99を算出します。
+func RunTCPClientRR(addr string, count int) (RRStats, error) {
+	tlsConf := &tls.Config{
+		InsecureSkipVerify: true, // サーバーは自己署名証明書のため検証をスキップ
+		NextProtos:         []string{"tcp-quic-bench"},
+	}
+
+	log.Println("Connecting via TCP for rr workload...")
+	conn, err := tls.Dial("tcp", addr, tlsConf)
+	if err != nil {
+		return RRStats{}, fmt.Errorf("failed to dial TCP: %w", err)
+	}
+	defer conn.Close()
+
+	req := make([]byte, rrPayloadSize)
+	resp := make([]byte, rrPayloadSize)
+	latencies := make([]time.Duration, 0, count)
+
+	for i := 0; i < count; i++ {
+		start := time.Now()
+		if _, err := conn.Write(req); err != nil {
+			return RRStats{}, fmt.Errorf("failed to send rr request: %w", err)
+		}
+		if _, err := io.ReadFull(conn, resp); err != nil {
+			return RRStats{}, fmt.Errorf("failed to receive rr response: %w", err)
+		}
+		latencies = append(latencies, time.Since(start))
+	}
+
+	log.Println("TCP rr workload complete.")
+	return rrStatsFromLatencies(latencies), nil
+}
+
+// RunQUICClientRR はQUICサーバーとの間でrrワークロードを実行します。1本のストリーム上でcount回の
+// 小さな往復を行い、各往復のレイテンシからp50/p95/p99を算出します。
+func RunQUICClientRR(addr string, count int) (RRStats, error) {
+	tlsConf := &tls.Config{
+		InsecureSkipVerify: true, // サーバーは自己署名証明書のため検証をスキップ
+		NextProtos:         []string{"tcp-quic-bench"},
+	}
+	quicConfig := &quic.Config{
+		DisablePathMTUDiscovery: true,
+		MaxIdleTimeout:          time.Minute,
+	}
+
+	log.Println("Connecting via QUIC for rr workload...")
+	conn, err := quic.DialAddr(context.Background(), addr, tlsConf, quicConfig)
+	if err != nil {
+		return RRStats{}, fmt.Errorf("failed to connect to QUIC server: %w", err)
+	}
+	defer conn.CloseWithError(0, "")
+
+	stream, err := conn.OpenStreamSync(context.Background())
+	if err != nil {
+		return RRStats{}, fmt.Errorf("failed to open QUIC stream: %w", err)
+	}
+	defer stream.Close()
+
+	req := make([]byte, rrPayloadSize)
+	resp := make([]byte, rrPayloadSize)
+	latencies := make([]time.Duration, 0, count)
+
+	for i := 0; i < count; i++ {
+		start := time.Now()
+		if _, err := stream.Write(req); err != nil {
+			return RRStats{}, fmt.Errorf("failed to send rr request: %w", err)
+		}
+		if _, err := io.ReadFull(stream, resp); err != nil {
+			return RRStats{}, fmt.Errorf("failed to receive rr response: %w", err)
+		}
+		latencies = append(latencies, time.Since(start))
+	}
+
+	log.Println("QUIC rr workload complete.")
+	return rrStatsFromLatencies(latencies), nil
+}
+
+// StreamStats はstreamsワークロードにおける単一ストリーム（TCPの場合は単一コネクション）の結果を表す。
+type StreamStats struct {
+	Bytes    int64
+	Duration time.Duration
+}
+
+// RunTCPClientStreams はnumStreams本のTCP+TLS接続を並行に張り、それぞれでstreamBytes分の
+// データを受信します。TCPはQUICのようにコネクション内でストリームを多重化できないため、
+// 並行コネクションで代替し、QUICのストリーム多重化との公平な比較対象とします。
+func RunTCPClientStreams(addr string, numStreams int, streamBytes int64) ([]StreamStats, error) {
+	results := make([]StreamStats, numStreams)
+	errs := make([]error, numStreams)
+	var wg sync.WaitGroup
+
+	for i := 0; i < numStreams; i++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+
+			tlsConf := &tls.Config{
+				InsecureSkipVerify: true,
+				NextProtos:         []string{"tcp-quic-bench"},
+			}
+
+			start := time.Now()
+			conn, err := tls.Dial("tcp", addr, tlsConf)
+			if err != nil {
+				errs[idx] = fmt.Errorf("failed to dial TCP stream %d: %w", idx, err)
+				return
+			}
+			defer conn.Close()
+
+			n, err := io.CopyN(io.Discard, conn, streamBytes)
+			if err != nil && err != io.EOF {
+				errs[idx] = fmt.Errorf("failed to receive TCP stream %d: %w", idx, err)
+				return
+			}
+			results[idx] = StreamStats{Bytes: n, Duration: time.Since(start)}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// RunQUICClientStreams は1本のQUIC接続上にnumStreams本のストリームを並行に開き、それぞれで
+// streamBytes分のデータを受信します。TCPの並行コネクションと異なり、全ストリームが単一の
+// コネクション（輻輳制御・暗号化コンテキスト）を共有するため、Head-of-Lineブロッキングの
+// 違いを観察できます。
+func RunQUICClientStreams(addr string, numStreams int, streamBytes int64) ([]StreamStats, error) {
+	tlsConf := &tls.Config{
+		InsecureSkipVerify: true,
+		NextProtos:         []string{"tcp-quic-bench"},
+	}
+	quicConfig := &quic.Config{
+		DisablePathMTUDiscovery: true,
+		MaxIdleTimeout:          time.Minute,
+	}
+
+	conn, err := quic.DialAddr(context.Background(), addr, tlsConf, quicConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to QUIC server: %w", err)
+	}
+	defer conn.CloseWithError(0, "")
+
+	results := make([]StreamStats, numStreams)
+	errs := make([]error, numStreams)
+	var wg sync.WaitGroup
+
+	for i := 0; i < numStreams; i++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+
+			start := time.Now()
+			stream, err := conn.AcceptStream(context.Background())
+			if err != nil {
+				errs[idx] = fmt.Errorf("failed to accept QUIC stream %d: %w", idx, err)
+				return
+			}
+			defer stream.Close()
+
+			n, err := io.CopyN(io.Discard, stream, streamBytes)
+			if err != nil && err != io.EOF {
+				errs[idx] = fmt.Errorf("failed to receive QUIC stream %d: %w", idx, err)
+				return
+			}
+			results[idx] = StreamStats{Bytes: n, Duration: time.Since(start)}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,49 @@
+package data
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// FileSource は、指定したファイルをmmapし、再現可能な固定ペイロードとして配信する
+// Sourceである。複数コネクションでmmap済みのページを共有できるため、ファイルが大きくても
+// プロセスごとの実メモリ消費はページキャッシュに委ねられる。
+type FileSource struct {
+	data []byte
+}
+
+// NewFileSource は、pathのファイルをmmapしてFileSourceを返す。
+func NewFileSource(path string) (*FileSource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open payload file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat payload file %s: %w", path, err)
+	}
+	if fi.Size() == 0 {
+		return nil, fmt.Errorf("payload file %s is empty", path)
+	}
+
+	mapped, err := unix.Mmap(int(f.Fd()), 0, int(fi.Size()), unix.PROT_READ, unix.MAP_SHARED)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mmap payload file %s: %w", path, err)
+	}
+
+	return &FileSource{data: mapped}, nil
+}
+
+func (s *FileSource) Reader() io.Reader {
+	return bytes.NewReader(s.data)
+}
+
+func (s *FileSource) Size() int64 {
+	return int64(len(s.data))
+}
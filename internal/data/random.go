@@ -0,0 +1,26 @@
+package data
+
+import (
+	"crypto/rand"
+	"io"
+)
+
+// RandomSource は、crypto/randから読み出した乱数データをsizeバイトぶんストリーミング
+// するSourceである。巨大な[]byteを確保せずに済むよう、Reader()はcrypto/randそのものを
+// io.LimitReaderでラップして返す。
+type RandomSource struct {
+	size int64
+}
+
+// NewRandomSource は、sizeバイトの乱数データを生成するRandomSourceを返す。
+func NewRandomSource(size int64) *RandomSource {
+	return &RandomSource{size: size}
+}
+
+func (s *RandomSource) Reader() io.Reader {
+	return io.LimitReader(rand.Reader, s.size)
+}
+
+func (s *RandomSource) Size() int64 {
+	return s.size
+}
@@ -0,0 +1,42 @@
+// data パッケージは、ベンチマークで送信するペイロードを表すSourceインターフェースと、
+// その実装（乱数・ゼロ埋め・ファイルmmap）を提供します。
+package data
+
+import (
+	"fmt"
+	"io"
+)
+
+// Source は、ベンチマークで送信するペイロードデータを表す。複数コネクションが同じ
+// ペイロードをそれぞれ独立に読み出せるよう、Reader は呼び出すたびに先頭から読み直せる
+// 新しいio.Readerを返す。
+type Source interface {
+	// Reader は、ペイロードの先頭から読み出す新しいio.Readerを返す。
+	Reader() io.Reader
+	// Size は、ペイロードの総バイト数を返す。
+	Size() int64
+}
+
+// sizedSource は、基となるSourceの先頭sizeバイトだけを切り出すSourceである。
+type sizedSource struct {
+	underlying Source
+	size       int64
+}
+
+// WithSize は、underlyingの先頭sizeバイトだけを公開するSourceを返す。streamsワークロードの
+// ように、payload生成方法（random/zero/file）に関わらず接続あたり正確にsizeバイトを送信
+// したい場合に使う。underlyingがsizeバイトに満たない場合はエラーを返す。
+func WithSize(underlying Source, size int64) (Source, error) {
+	if underlying.Size() < size {
+		return nil, fmt.Errorf("payload source has only %d bytes, need at least %d", underlying.Size(), size)
+	}
+	return &sizedSource{underlying: underlying, size: size}, nil
+}
+
+func (s *sizedSource) Reader() io.Reader {
+	return io.LimitReader(s.underlying.Reader(), s.size)
+}
+
+func (s *sizedSource) Size() int64 {
+	return s.size
+}
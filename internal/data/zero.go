@@ -0,0 +1,32 @@
+package data
+
+import "io"
+
+// ZeroSource は、すべて0のバイトをsizeバイトぶん返すSourceである。crypto/randによる
+// データ生成コストを排除し、純粋なネットワークスループットを測定したい場合に使う。
+type ZeroSource struct {
+	size int64
+}
+
+// NewZeroSource は、sizeバイトのゼロ埋めデータを返すZeroSourceを返す。
+func NewZeroSource(size int64) *ZeroSource {
+	return &ZeroSource{size: size}
+}
+
+func (s *ZeroSource) Reader() io.Reader {
+	return io.LimitReader(zeroReader{}, s.size)
+}
+
+func (s *ZeroSource) Size() int64 {
+	return s.size
+}
+
+// zeroReader は、読み出すたびにpを0で埋める無限io.Readerである。
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
@@ -0,0 +1,234 @@
+// netem パッケージは、net/tcやrootなしでも使える簡易的なネットワークエミュレーション層を提供します。
+// net.PacketConn（QUIC向け）とnet.Conn（TCP向け）をラップし、RTT・ジッタ・帯域上限・パケットロス・
+// 並び替えを注入することで、劣化したネットワーク条件下でのTCPとQUICの挙動を比較できるようにします。
+package netem
+
+import (
+	"container/heap"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// Config は注入するネットワーク劣化条件をまとめたものである。ゼロ値は「劣化なし」を意味する。
+type Config struct {
+	RTT          time.Duration // 片道遅延としてRTT/2を適用する
+	Jitter       time.Duration // 遅延に加えるランダムな揺らぎの最大値
+	LossPct      float64       // パケットロス率（0.0-1.0）。PacketConnのみ有効
+	ReorderPct   float64       // 並び替えが発生する確率（0.0-1.0）。PacketConnのみ有効
+	BandwidthBps int64         // 帯域上限（bit/s）。0の場合は無制限
+}
+
+// Enabled は、いずれかの劣化パラメータが設定されているかどうかを返す。
+func (c Config) Enabled() bool {
+	return c.RTT > 0 || c.Jitter > 0 || c.LossPct > 0 || c.ReorderPct > 0 || c.BandwidthBps > 0
+}
+
+// delay は、設定されたRTT/2とジッタから、1パケットあたりの遅延を決定する。
+func (c Config) delay(rng *rand.Rand) time.Duration {
+	d := c.RTT / 2
+	if c.Jitter > 0 {
+		d += time.Duration(rng.Int63n(int64(c.Jitter)))
+	}
+	return d
+}
+
+// bandwidthLimiter は、設定された帯域上限に基づき送信時刻をずらしていくトークンバケツ的な
+// 簡易実装である。直列化されたpacedAt以降にしか次の送信が開始できないようにする。
+type bandwidthLimiter struct {
+	bps int64
+
+	mu      sync.Mutex
+	pacedAt time.Time
+}
+
+// reserve はn バイトの送信にかかる伝送時間を予約し、実際に送信してよい時刻までの待ち時間を返す。
+func (b *bandwidthLimiter) reserve(n int) time.Duration {
+	if b.bps <= 0 {
+		return 0
+	}
+
+	transmitTime := time.Duration(float64(n*8) / float64(b.bps) * float64(time.Second))
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	start := now
+	if b.pacedAt.After(start) {
+		start = b.pacedAt
+	}
+	b.pacedAt = start.Add(transmitTime)
+	return start.Add(transmitTime).Sub(now)
+}
+
+// timedPacket は、配送予定時刻が設定されたUDPパケットを表す。
+type timedPacket struct {
+	deliverAt time.Time
+	data      []byte
+	addr      net.Addr
+}
+
+// packetHeap は、配送予定時刻が最も早いパケットを取り出せるcontainer/heap実装である。
+type packetHeap []*timedPacket
+
+func (h packetHeap) Len() int            { return len(h) }
+func (h packetHeap) Less(i, j int) bool  { return h[i].deliverAt.Before(h[j].deliverAt) }
+func (h packetHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *packetHeap) Push(x interface{}) { *h = append(*h, x.(*timedPacket)) }
+func (h *packetHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// PacketConn は net.PacketConn をラップし、WriteToで送信されるパケットに遅延・ジッタ・帯域上限・
+// パケットロス・並び替えを注入する。QUIC向けにquic.Transport.Connへ渡すことを想定している。
+type PacketConn struct {
+	net.PacketConn
+	cfg     Config
+	limiter *bandwidthLimiter
+	rng     *rand.Rand
+
+	mu      sync.Mutex
+	pending packetHeap
+	wakeCh  chan struct{}
+	closeCh chan struct{}
+}
+
+// NewPacketConn は、cfgで指定された劣化条件を注入するPacketConnを生成する。
+func NewPacketConn(conn net.PacketConn, cfg Config) *PacketConn {
+	p := &PacketConn{
+		PacketConn: conn,
+		cfg:        cfg,
+		limiter:    &bandwidthLimiter{bps: cfg.BandwidthBps},
+		rng:        rand.New(rand.NewSource(time.Now().UnixNano())),
+		wakeCh:     make(chan struct{}, 1),
+		closeCh:    make(chan struct{}),
+	}
+	go p.deliveryLoop()
+	return p
+}
+
+// WriteTo は、パケットロスと並び替えを確率的に適用したうえで、遅延キューにパケットを積む。
+// 実際の送信はdeliveryLoopが配送予定時刻になった時点で行う。
+func (p *PacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	if p.cfg.LossPct > 0 && p.rng.Float64() < p.cfg.LossPct {
+		// パケットロスをエミュレートする。呼び出し元には送信成功として返す
+		// （UDPの送達保証がない性質上、これは正しい挙動である）。
+		return len(b), nil
+	}
+
+	delay := p.cfg.delay(p.rng) + p.limiter.reserve(len(b))
+	deliverAt := time.Now().Add(delay)
+	if p.cfg.ReorderPct > 0 && p.rng.Float64() < p.cfg.ReorderPct {
+		// 並び替えをエミュレートするため、追加の遅延を加えて後続のパケットを先に届かせる。
+		deliverAt = deliverAt.Add(delay + p.cfg.Jitter)
+	}
+
+	data := make([]byte, len(b))
+	copy(data, b)
+
+	p.mu.Lock()
+	heap.Push(&p.pending, &timedPacket{deliverAt: deliverAt, data: data, addr: addr})
+	p.mu.Unlock()
+
+	select {
+	case p.wakeCh <- struct{}{}:
+	default:
+	}
+
+	return len(b), nil
+}
+
+// deliveryLoop は、配送キューの先頭パケットの配送予定時刻まで待機し、時刻が来たら
+// 実際の下位コネクションへ書き込む。
+func (p *PacketConn) deliveryLoop() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		p.mu.Lock()
+		var wait time.Duration
+		if len(p.pending) == 0 {
+			wait = time.Hour
+		} else {
+			wait = time.Until(p.pending[0].deliverAt)
+			if wait < 0 {
+				wait = 0
+			}
+		}
+		p.mu.Unlock()
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-p.closeCh:
+			return
+		case <-p.wakeCh:
+			continue
+		case <-timer.C:
+			p.mu.Lock()
+			var pkt *timedPacket
+			if len(p.pending) > 0 && !time.Now().Before(p.pending[0].deliverAt) {
+				pkt = heap.Pop(&p.pending).(*timedPacket)
+			}
+			p.mu.Unlock()
+
+			if pkt == nil {
+				continue
+			}
+			if _, err := p.PacketConn.WriteTo(pkt.data, pkt.addr); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Close は配送ループを停止し、下位のPacketConnをクローズする。
+func (p *PacketConn) Close() error {
+	select {
+	case <-p.closeCh:
+	default:
+		close(p.closeCh)
+	}
+	return p.PacketConn.Close()
+}
+
+// Conn は net.Conn をラップし、Writeに遅延と帯域上限を注入する。TCPはバイトストリームであり
+// 順序保証と再送は輸送層が担うため、パケットロスや並び替えの注入はサポートしない
+// （それらはUDP/QUIC向けのPacketConnでのみ意味を持つ）。
+type Conn struct {
+	net.Conn
+	cfg     Config
+	limiter *bandwidthLimiter
+	rng     *rand.Rand
+}
+
+// NewConn は、cfgで指定された遅延・帯域上限を注入するConnを生成する。
+func NewConn(conn net.Conn, cfg Config) *Conn {
+	return &Conn{
+		Conn:    conn,
+		cfg:     cfg,
+		limiter: &bandwidthLimiter{bps: cfg.BandwidthBps},
+		rng:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Write は、設定された遅延と帯域上限ぶんだけ書き込みを遅らせてから、下位のConnへ書き込む。
+func (c *Conn) Write(b []byte) (int, error) {
+	delay := c.cfg.delay(c.rng) + c.limiter.reserve(len(b))
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	return c.Conn.Write(b)
+}
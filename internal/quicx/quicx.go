@@ -0,0 +1,95 @@
+// quicx パッケージは、QUICの輻輳制御比較に関する可観測性ユーティリティを提供します。
+// quic-goは輻輳制御アルゴリズムの切り替えを公開APIとして提供していないため、ここでは代わりに
+// quic.Config.Tracerフックを使ってcwnd・送達未確認バイト数（bytes in flight）・RTTの推移を
+// 記録し、CSVとして書き出せるようにします。-ccフラグで指定されたアルゴリズム名は、記録された
+// サンプルにラベルとして付与され、TCP側のTCP_CONGESTIONソケットオプションとの比較に使えます。
+package quicx
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/logging"
+)
+
+// CCSample は、ある時点における輻輳制御状態のスナップショットを表す。
+type CCSample struct {
+	Time             time.Time
+	CongestionWindow logging.ByteCount
+	BytesInFlight    logging.ByteCount
+	SmoothedRTT      time.Duration
+}
+
+// CCLogger は、CCSampleを蓄積し、CSVとして書き出すためのロガーである。同じCCLoggerを
+// 複数の接続で使った場合、全接続のサンプルが接続の区別なく1つの列に蓄積される。
+// ccAlgoには-ccフラグで指定されたアルゴリズム名（cubic, reno, bbrなど、ラベル用）を保持する。
+type CCLogger struct {
+	ccAlgo string
+
+	mu      sync.Mutex
+	samples []CCSample
+}
+
+// NewCCLogger は、ccAlgo（CSV出力のラベルに使うアルゴリズム名）に紐づくCCLoggerを生成する。
+func NewCCLogger(ccAlgo string) *CCLogger {
+	return &CCLogger{ccAlgo: ccAlgo}
+}
+
+// add はサンプルを1件追加する。
+func (l *CCLogger) add(s CCSample) {
+	l.mu.Lock()
+	l.samples = append(l.samples, s)
+	l.mu.Unlock()
+}
+
+// WriteCSV は、蓄積したサンプルをCSV形式でwに書き出す。
+func (l *CCLogger) WriteCSV(w io.Writer) error {
+	l.mu.Lock()
+	samples := make([]CCSample, len(l.samples))
+	copy(samples, l.samples)
+	l.mu.Unlock()
+
+	cw := csv.NewWriter(w)
+	defer cw.Flush()
+
+	if err := cw.Write([]string{"time", "cc_algo", "cwnd_bytes", "bytes_in_flight", "smoothed_rtt_ms"}); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	for _, s := range samples {
+		row := []string{
+			s.Time.Format(time.RFC3339Nano),
+			l.ccAlgo,
+			fmt.Sprintf("%d", s.CongestionWindow),
+			fmt.Sprintf("%d", s.BytesInFlight),
+			fmt.Sprintf("%.3f", s.SmoothedRTT.Seconds()*1000),
+		}
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+	return cw.Error()
+}
+
+// NewTracerFactory は、quic.Config.Tracerに設定するためのトレーサーファクトリを返す。
+// 返されたファクトリが生成するConnectionTracerは、quic-goがRTT・cwnd・bytes in flightの
+// メトリクスを更新するたびに（UpdatedMetrics）loggerへサンプルを追加する。
+func NewTracerFactory(logger *CCLogger) func(ctx context.Context, p logging.Perspective, connID quic.ConnectionID) *logging.ConnectionTracer {
+	return func(ctx context.Context, p logging.Perspective, connID quic.ConnectionID) *logging.ConnectionTracer {
+		return &logging.ConnectionTracer{
+			UpdatedCongestionState: func(state logging.CongestionState) {},
+			UpdatedMetrics: func(rttStats *logging.RTTStats, cwnd, bytesInFlight logging.ByteCount, packetsInFlight int) {
+				logger.add(CCSample{
+					Time:             time.Now(),
+					CongestionWindow: cwnd,
+					BytesInFlight:    bytesInFlight,
+					SmoothedRTT:      rttStats.SmoothedRTT(),
+				})
+			},
+		}
+	}
+}
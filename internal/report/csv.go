@@ -0,0 +1,83 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+// csvReporter は、結果をヘッダー行+1行（streamsの場合はストリームごとに1行と、合計
+// スループットの"aggregate"行）のCSVとしてwに書き出す。
+type csvReporter struct {
+	w io.Writer
+}
+
+func (c *csvReporter) writeRows(header []string, rows [][]string) error {
+	cw := csv.NewWriter(c.w)
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	for _, row := range rows {
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (c *csvReporter) ReportBulk(r BulkReport) error {
+	header := []string{
+		"total_bytes", "measurement_runs",
+		"handshake_mean_s", "handshake_stddev_s", "handshake_p50_s", "handshake_p95_s", "handshake_p99_s",
+		"zero_rtt_handshake_mean_s", "zero_rtt_resumed_runs",
+		"data_transfer_mean_s", "data_transfer_stddev_s", "data_transfer_p50_s", "data_transfer_p95_s", "data_transfer_p99_s",
+		"total_mean_s", "total_stddev_s",
+		"throughput_mean_gbps",
+	}
+
+	var zeroRTTMean, zeroRTTResumed string
+	if r.ZeroRTTHandshake != nil {
+		zeroRTTMean = fmt.Sprintf("%.6f", r.ZeroRTTHandshake.MeanSeconds)
+		zeroRTTResumed = fmt.Sprintf("%d", r.ZeroRTTResumedRuns)
+	}
+
+	row := []string{
+		fmt.Sprintf("%d", r.TotalBytes), fmt.Sprintf("%d", r.MeasurementRuns),
+		fmt.Sprintf("%.6f", r.Handshake.MeanSeconds), fmt.Sprintf("%.6f", r.Handshake.StdDevSeconds),
+		fmt.Sprintf("%.6f", r.Handshake.P50Seconds), fmt.Sprintf("%.6f", r.Handshake.P95Seconds), fmt.Sprintf("%.6f", r.Handshake.P99Seconds),
+		zeroRTTMean, zeroRTTResumed,
+		fmt.Sprintf("%.6f", r.DataTransfer.MeanSeconds), fmt.Sprintf("%.6f", r.DataTransfer.StdDevSeconds),
+		fmt.Sprintf("%.6f", r.DataTransfer.P50Seconds), fmt.Sprintf("%.6f", r.DataTransfer.P95Seconds), fmt.Sprintf("%.6f", r.DataTransfer.P99Seconds),
+		fmt.Sprintf("%.6f", r.Total.MeanSeconds), fmt.Sprintf("%.6f", r.Total.StdDevSeconds),
+		fmt.Sprintf("%.6f", r.ThroughputMeanGbps),
+	}
+
+	return c.writeRows(header, [][]string{row})
+}
+
+func (c *csvReporter) ReportRR(r RRReport) error {
+	header := []string{"count", "p50_s", "p95_s", "p99_s"}
+	row := []string{
+		fmt.Sprintf("%d", r.Count),
+		fmt.Sprintf("%.6f", r.P50Seconds),
+		fmt.Sprintf("%.6f", r.P95Seconds),
+		fmt.Sprintf("%.6f", r.P99Seconds),
+	}
+	return c.writeRows(header, [][]string{row})
+}
+
+func (c *csvReporter) ReportStreams(r StreamsReport) error {
+	header := []string{"stream_index", "bytes", "duration_s", "throughput_gbps"}
+	rows := make([][]string, 0, len(r.Streams)+1)
+	for _, s := range r.Streams {
+		rows = append(rows, []string{
+			fmt.Sprintf("%d", s.Index),
+			fmt.Sprintf("%d", s.Bytes),
+			fmt.Sprintf("%.6f", s.DurationSeconds),
+			fmt.Sprintf("%.6f", s.ThroughputGbps),
+		})
+	}
+	rows = append(rows, []string{"aggregate", "", "", fmt.Sprintf("%.6f", r.AggregateThroughputGbps)})
+	return c.writeRows(header, rows)
+}
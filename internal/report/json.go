@@ -0,0 +1,24 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// jsonReporter は、結果を1行のJSONオブジェクトとしてwに書き出す。
+type jsonReporter struct {
+	w io.Writer
+}
+
+func (j *jsonReporter) write(v interface{}) error {
+	enc := json.NewEncoder(j.w)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode JSON report: %w", err)
+	}
+	return nil
+}
+
+func (j *jsonReporter) ReportBulk(r BulkReport) error       { return j.write(r) }
+func (j *jsonReporter) ReportRR(r RRReport) error           { return j.write(r) }
+func (j *jsonReporter) ReportStreams(r StreamsReport) error { return j.write(r) }
@@ -0,0 +1,67 @@
+package report
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Metrics は、直近のベンチマーク実行結果を保持し、Prometheusのテキスト形式で公開するための
+// スレッドセーフなレジストリである。長時間のベンチマークスイープ中に外部からスクレイプできる
+// よう、既存のpprofサーバーが使うHTTPマルチプレクサに/metricsとして登録することを想定している。
+type Metrics struct {
+	mu sync.RWMutex
+
+	runsTotal      int
+	lastBytes      int64
+	lastHandshake  time.Duration
+	lastDataXfer   time.Duration
+	lastThroughput float64 // Gbps
+}
+
+// NewMetrics は空のMetricsレジストリを生成する。
+func NewMetrics() *Metrics {
+	return &Metrics{}
+}
+
+// Observe は、1回分のベンチマーク実行結果を記録する。
+func (m *Metrics) Observe(bytes int64, handshake, dataTransfer time.Duration, throughputGbps float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runsTotal++
+	m.lastBytes = bytes
+	m.lastHandshake = handshake
+	m.lastDataXfer = dataTransfer
+	m.lastThroughput = throughputGbps
+}
+
+// Handler は、直近の計測値をPrometheusのテキスト形式で返すhttp.Handlerを返す。
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+		fmt.Fprintln(w, "# HELP tcp_quic_bench_runs_total Number of completed benchmark runs.")
+		fmt.Fprintln(w, "# TYPE tcp_quic_bench_runs_total counter")
+		fmt.Fprintf(w, "tcp_quic_bench_runs_total %d\n", m.runsTotal)
+
+		fmt.Fprintln(w, "# HELP tcp_quic_bench_last_run_bytes Bytes transferred in the most recent run.")
+		fmt.Fprintln(w, "# TYPE tcp_quic_bench_last_run_bytes gauge")
+		fmt.Fprintf(w, "tcp_quic_bench_last_run_bytes %d\n", m.lastBytes)
+
+		fmt.Fprintln(w, "# HELP tcp_quic_bench_last_handshake_seconds Handshake duration of the most recent run.")
+		fmt.Fprintln(w, "# TYPE tcp_quic_bench_last_handshake_seconds gauge")
+		fmt.Fprintf(w, "tcp_quic_bench_last_handshake_seconds %.6f\n", m.lastHandshake.Seconds())
+
+		fmt.Fprintln(w, "# HELP tcp_quic_bench_last_data_transfer_seconds Data transfer duration of the most recent run.")
+		fmt.Fprintln(w, "# TYPE tcp_quic_bench_last_data_transfer_seconds gauge")
+		fmt.Fprintf(w, "tcp_quic_bench_last_data_transfer_seconds %.6f\n", m.lastDataXfer.Seconds())
+
+		fmt.Fprintln(w, "# HELP tcp_quic_bench_last_throughput_gbps Throughput of the most recent run in Gbps.")
+		fmt.Fprintln(w, "# TYPE tcp_quic_bench_last_throughput_gbps gauge")
+		fmt.Fprintf(w, "tcp_quic_bench_last_throughput_gbps %.6f\n", m.lastThroughput)
+	})
+}
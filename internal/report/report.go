@@ -0,0 +1,144 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Shinonome517/tcp-quic-bench/internal/client"
+)
+
+// BulkReport は、bulkワークロードの計測結果をまとめたものである。
+type BulkReport struct {
+	TotalBytes         int64   `json:"total_bytes"`
+	MeasurementRuns    int     `json:"measurement_runs"`
+	Handshake          Stats   `json:"handshake"`
+	ZeroRTTHandshake   *Stats  `json:"zero_rtt_handshake,omitempty"`
+	ZeroRTTResumedRuns int     `json:"zero_rtt_resumed_runs,omitempty"`
+	DataTransfer       Stats   `json:"data_transfer"`
+	Total              Stats   `json:"total"`
+	ThroughputMeanGbps float64 `json:"throughput_mean_gbps"`
+}
+
+// NewBulkReport は、総バイト数と、計測実行ごとのハンドシェイク時間・0-RTTハンドシェイク時間・
+// データ転送時間からBulkReportを構築する。zeroRTTDurationsが空の場合、ZeroRTTHandshakeは
+// nilのままとなる。
+func NewBulkReport(totalBytes int64, handshakeDurations, zeroRTTDurations, dataTransferDurations []time.Duration) BulkReport {
+	totalDurations := make([]time.Duration, len(handshakeDurations))
+	for i := range handshakeDurations {
+		totalDurations[i] = handshakeDurations[i] + dataTransferDurations[i]
+	}
+
+	var throughputs []float64
+	for _, d := range totalDurations {
+		if s := d.Seconds(); s > 0 {
+			throughputs = append(throughputs, (float64(totalBytes)*8)/(s*1e9))
+		}
+	}
+	var sumThroughput float64
+	for _, t := range throughputs {
+		sumThroughput += t
+	}
+	var meanThroughput float64
+	if len(throughputs) > 0 {
+		meanThroughput = sumThroughput / float64(len(throughputs))
+	}
+
+	r := BulkReport{
+		TotalBytes:         totalBytes,
+		MeasurementRuns:    len(handshakeDurations),
+		Handshake:          statsFromDurations(handshakeDurations),
+		DataTransfer:       statsFromDurations(dataTransferDurations),
+		Total:              statsFromDurations(totalDurations),
+		ThroughputMeanGbps: meanThroughput,
+	}
+	if len(zeroRTTDurations) > 0 {
+		zeroRTT := statsFromDurations(zeroRTTDurations)
+		r.ZeroRTTHandshake = &zeroRTT
+		r.ZeroRTTResumedRuns = len(zeroRTTDurations)
+	}
+	return r
+}
+
+// RRReport は、rr（リクエスト/レスポンス）ワークロードの往復レイテンシ統計を表す。
+type RRReport struct {
+	Count      int     `json:"count"`
+	P50Seconds float64 `json:"p50_seconds"`
+	P95Seconds float64 `json:"p95_seconds"`
+	P99Seconds float64 `json:"p99_seconds"`
+}
+
+// NewRRReport は、client.RRStatsからRRReportを構築する。
+func NewRRReport(stats client.RRStats) RRReport {
+	return RRReport{
+		Count:      stats.Count,
+		P50Seconds: stats.P50.Seconds(),
+		P95Seconds: stats.P95.Seconds(),
+		P99Seconds: stats.P99.Seconds(),
+	}
+}
+
+// StreamReportEntry は、streamsワークロードにおける単一ストリームの結果を表す。
+type StreamReportEntry struct {
+	Index           int     `json:"index"`
+	Bytes           int64   `json:"bytes"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	ThroughputGbps  float64 `json:"throughput_gbps"`
+}
+
+// StreamsReport は、streamsワークロードの計測結果をまとめたものである。
+type StreamsReport struct {
+	Streams                 []StreamReportEntry `json:"streams"`
+	AggregateThroughputGbps float64             `json:"aggregate_throughput_gbps"`
+}
+
+// NewStreamsReport は、[]client.StreamStatsからStreamsReportを構築する。全ストリームが
+// 並行に走るため、合計スループットは最も遅いストリームの所要時間を使って算出する。
+func NewStreamsReport(stats []client.StreamStats) StreamsReport {
+	r := StreamsReport{Streams: make([]StreamReportEntry, len(stats))}
+
+	var totalBytes int64
+	var maxDuration time.Duration
+	for i, s := range stats {
+		var throughput float64
+		if sec := s.Duration.Seconds(); sec > 0 {
+			throughput = (float64(s.Bytes) * 8) / (sec * 1e9)
+		}
+		r.Streams[i] = StreamReportEntry{
+			Index:           i,
+			Bytes:           s.Bytes,
+			DurationSeconds: s.Duration.Seconds(),
+			ThroughputGbps:  throughput,
+		}
+		totalBytes += s.Bytes
+		if s.Duration > maxDuration {
+			maxDuration = s.Duration
+		}
+	}
+	if maxDuration > 0 {
+		r.AggregateThroughputGbps = (float64(totalBytes) * 8) / (maxDuration.Seconds() * 1e9)
+	}
+	return r
+}
+
+// Reporter は、ベンチマーク結果を何らかの形式で出力するインターフェースである。
+type Reporter interface {
+	ReportBulk(r BulkReport) error
+	ReportRR(r RRReport) error
+	ReportStreams(r StreamsReport) error
+}
+
+// NewReporter は、formatに対応するReporterを生成する。formatは"text"、"json"、"csv"の
+// いずれかでなければならない。
+func NewReporter(format string, w io.Writer) (Reporter, error) {
+	switch format {
+	case "text", "":
+		return &textReporter{w: w}, nil
+	case "json":
+		return &jsonReporter{w: w}, nil
+	case "csv":
+		return &csvReporter{w: w}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format: %s (must be text, json or csv)", format)
+	}
+}
@@ -0,0 +1,54 @@
+// report パッケージは、ベンチマーク結果を人間向けテキスト・JSON・CSVの各形式で出力する
+// ためのReporterと、実行中のベンチマークをPrometheusのテキスト形式で公開するMetricsを
+// 提供します。
+package report
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/Shinonome517/tcp-quic-bench/internal/stats"
+)
+
+// Stats は、一連の計測値から求めた平均・標準偏差・p50/p95/p99パーセンタイルをまとめた
+// ものである。秒単位のfloat64で保持し、JSON/CSV出力にそのまま使えるようにする。
+type Stats struct {
+	MeanSeconds   float64 `json:"mean_seconds"`
+	StdDevSeconds float64 `json:"stddev_seconds"`
+	P50Seconds    float64 `json:"p50_seconds"`
+	P95Seconds    float64 `json:"p95_seconds"`
+	P99Seconds    float64 `json:"p99_seconds"`
+}
+
+// statsFromDurations は、durationsから平均・標準偏差・p50/p95/p99を計算する。
+func statsFromDurations(durations []time.Duration) Stats {
+	if len(durations) == 0 {
+		return Stats{}
+	}
+
+	var sum float64
+	for _, d := range durations {
+		sum += d.Seconds()
+	}
+	mean := sum / float64(len(durations))
+
+	var sumSqDiff float64
+	for _, d := range durations {
+		diff := d.Seconds() - mean
+		sumSqDiff += diff * diff
+	}
+	stdDev := math.Sqrt(sumSqDiff / float64(len(durations)))
+
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return Stats{
+		MeanSeconds:   mean,
+		StdDevSeconds: stdDev,
+		P50Seconds:    stats.Percentile(sorted, 50).Seconds(),
+		P95Seconds:    stats.Percentile(sorted, 95).Seconds(),
+		P99Seconds:    stats.Percentile(sorted, 99).Seconds(),
+	}
+}
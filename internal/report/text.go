@@ -0,0 +1,71 @@
+package report
+
+import (
+	"fmt"
+	"io"
+)
+
+// textReporter は、結果を人間向けに整形したテキストとしてwに書き出す。
+type textReporter struct {
+	w io.Writer
+}
+
+func (t *textReporter) ReportBulk(r BulkReport) error {
+	fmt.Fprintln(t.w, "\n--- Benchmark Results ---")
+	fmt.Fprintf(t.w, "Total bytes received per run: %d bytes\n", r.TotalBytes)
+	fmt.Fprintf(t.w, "Number of measurement runs: %d\n", r.MeasurementRuns)
+	fmt.Fprintln(t.w, "-------------------------")
+
+	fmt.Fprintf(t.w, "Handshake time (Mean):      %.4f s\n", r.Handshake.MeanSeconds)
+	fmt.Fprintf(t.w, "Handshake time (StdDev):    %.4f s\n", r.Handshake.StdDevSeconds)
+	fmt.Fprintln(t.w, "-------------------------")
+
+	if r.ZeroRTTHandshake != nil {
+		fmt.Fprintf(t.w, "0-RTT handshake (Mean):     %.4f s\n", r.ZeroRTTHandshake.MeanSeconds)
+		fmt.Fprintf(t.w, "0-RTT handshake (StdDev):   %.4f s\n", r.ZeroRTTHandshake.StdDevSeconds)
+		fmt.Fprintf(t.w, "0-RTT resumed runs:         %d/%d\n", r.ZeroRTTResumedRuns, r.MeasurementRuns)
+		fmt.Fprintln(t.w, "-------------------------")
+	}
+
+	fmt.Fprintf(t.w, "Data transfer time (Mean):  %.4f s\n", r.DataTransfer.MeanSeconds)
+	fmt.Fprintf(t.w, "Data transfer time (StdDev): %.4f s\n", r.DataTransfer.StdDevSeconds)
+	fmt.Fprintln(t.w, "-------------------------")
+
+	fmt.Fprintf(t.w, "Total time (Mean):          %.4f s\n", r.Total.MeanSeconds)
+	fmt.Fprintf(t.w, "Total time (StdDev):        %.4f s\n", r.Total.StdDevSeconds)
+	fmt.Fprintln(t.w, "-------------------------")
+
+	fmt.Fprintf(t.w, "Throughput (Mean):          %.4f Gbps\n", r.ThroughputMeanGbps)
+	fmt.Fprintln(t.w, "-------------------------")
+
+	fmt.Fprintf(t.w, "Handshake latency p50/p95/p99:     %.6f / %.6f / %.6f s\n", r.Handshake.P50Seconds, r.Handshake.P95Seconds, r.Handshake.P99Seconds)
+	fmt.Fprintf(t.w, "Data transfer latency p50/p95/p99:  %.6f / %.6f / %.6f s\n", r.DataTransfer.P50Seconds, r.DataTransfer.P95Seconds, r.DataTransfer.P99Seconds)
+	fmt.Fprintln(t.w, "-------------------------")
+	return nil
+}
+
+func (t *textReporter) ReportRR(r RRReport) error {
+	fmt.Fprintln(t.w, "\n--- rr Workload Results ---")
+	fmt.Fprintf(t.w, "Round trips: %d\n", r.Count)
+	fmt.Fprintln(t.w, "-------------------------")
+	fmt.Fprintf(t.w, "Latency p50: %.6f s\n", r.P50Seconds)
+	fmt.Fprintf(t.w, "Latency p95: %.6f s\n", r.P95Seconds)
+	fmt.Fprintf(t.w, "Latency p99: %.6f s\n", r.P99Seconds)
+	fmt.Fprintln(t.w, "-------------------------")
+	return nil
+}
+
+func (t *textReporter) ReportStreams(r StreamsReport) error {
+	fmt.Fprintln(t.w, "\n--- streams Workload Results ---")
+	for _, s := range r.Streams {
+		fmt.Fprintf(t.w, "Stream %d: %d bytes in %.4f s (%.4f Gbps)\n", s.Index, s.Bytes, s.DurationSeconds, s.ThroughputGbps)
+	}
+	fmt.Fprintln(t.w, "-------------------------")
+
+	if r.AggregateThroughputGbps > 0 {
+		// 全ストリームが並行に走るため、経過時間は最も遅いストリームの所要時間を使う
+		fmt.Fprintf(t.w, "Aggregate throughput (wall-clock): %.4f Gbps\n", r.AggregateThroughputGbps)
+	}
+	fmt.Fprintln(t.w, "-------------------------")
+	return nil
+}
@@ -5,21 +5,53 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"net/http"
+	"sync"
+	"syscall"
 	"time"
 	// pprofのためのブランクインポート。これはpprofをインクルードする標準的な方法です。
 	_ "net/http/pprof"
 
+	"github.com/Shinonome517/tcp-quic-bench/internal/data"
+	"github.com/Shinonome517/tcp-quic-bench/internal/netem"
+	"github.com/Shinonome517/tcp-quic-bench/internal/report"
 	tlsutil "github.com/Shinonome517/tcp-quic-bench/internal/tls"
 	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
 	"golang.org/x/sys/unix"
 )
 
+// rrPayloadSize は rr ワークロードにおける1回のリクエスト/レスポンスあたりのペイロードサイズです。
+// internal/clientが送信するリクエストのサイズと一致させる必要があります。
+const rrPayloadSize = 64
+
+// RunOptions は、bulkワークロードのRunTCPServer/RunQUICServerの挙動を制御する
+// 共通オプションをまとめたものである。フラグが増えるたびにこの構造体へフィールドを
+// 追加していく。QUICの輻輳制御メトリクス収集（internal/quicx）は、計測ループが完結し
+// CSVを書き出す自然なタイミングを持つクライアント側でのみ提供する（server側は接続を
+// 受け付け続けるループのため、フラッシュする機会がない）。
+type RunOptions struct {
+	ZeroRTT  bool
+	NetemCfg *netem.Config // nilの場合、ネットワークエミュレーションは無効
+	CC       string        // 輻輳制御アルゴリズム名（cubic, reno, bbr）。空文字の場合はOS/quic-goのデフォルトのまま
+}
+
+// Metrics は、直近のbulkワークロード実行（ハンドシェイク時間・転送時間・スループット・
+// バイト数）を記録するレジストリである。pprofServerがリッスンするHTTPマルチプレクサに
+// /metricsとして登録され、長時間のベンチマークスイープ中に外部からスクレイプできる。
+var Metrics = report.NewMetrics()
+
+var registerMetricsOnce sync.Once
+
 // pprofServer は、pprofデータを提供するためにlocalhost:6060でHTTPサーバーを開始します。
 // この関数はブロッキングするため、別のゴルーチンで実行する必要があります。
 func pprofServer() {
+	registerMetricsOnce.Do(func() {
+		http.Handle("/metrics", Metrics.Handler())
+	})
 	log.Println("Starting pprof server on :6060")
 	if err := http.ListenAndServe("localhost:6060", nil); err != nil {
 		log.Fatalf("pprof server failed: %v", err)
@@ -27,8 +59,14 @@ func pprofServer() {
 }
 
 // RunTCPServer は、指定されたアドレスでTCPサーバーを開始します。接続してきたクライアントに
-// 提供されたデータを送信します。
-func RunTCPServer(addr string, data []byte) error {
+// sourceの内容をストリーミングで送信します。各接続ごとにsource.Reader()で新しいReaderを
+// 取得するため、sourceは[]byteとして保持されるとは限らず、巨大なペイロードでも1接続ぶんを
+// メモリに確保せずに配信できます。opts.ZeroRTTがtrueの場合、リスニングソケットにTCP Fast Open
+// （TCP_FASTOPEN）を設定し、TLS 1.3セッション再開によるクライアントの0-RTT接続を受け付けます。
+// opts.NetemCfgが非nilの場合、各接続にRTT・帯域等のネットワーク劣化条件を注入します。
+// opts.CCが空文字でない場合、各接続にTCP_CONGESTIONソケットオプションで輻輳制御アルゴリズムを
+// 指定します。
+func RunTCPServer(addr string, source data.Source, opts RunOptions) error {
 	// pprofサーバーを別のゴルーチンで開始し、ブロッキングしないようにします。
 	go pprofServer()
 
@@ -38,8 +76,22 @@ func RunTCPServer(addr string, data []byte) error {
 		return fmt.Errorf("failed to setup TLS: %w", err)
 	}
 
+	lc := net.ListenConfig{}
+	if opts.ZeroRTT {
+		// TCP Fast Openを有効化し、SYNに相乗りしたデータを受け入れられるようにする
+		lc.Control = func(network, address string, c syscall.RawConn) error {
+			var sockErr error
+			if err := c.Control(func(fd uintptr) {
+				sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_FASTOPEN, 5)
+			}); err != nil {
+				return err
+			}
+			return sockErr
+		}
+	}
+
 	// TCPリスナーを生成
-	l, err := net.Listen("tcp", addr)
+	l, err := lc.Listen(context.Background(), "tcp", addr)
 	if err != nil {
 		return fmt.Errorf("failed to listen on %s: %w", addr, err)
 	}
@@ -73,6 +125,11 @@ func RunTCPServer(addr string, data []byte) error {
 			if err != nil {
 				log.Printf("failed to set TCP_MAXSEG: %v", err)
 			}
+			if opts.CC != "" {
+				if err := unix.SetsockoptString(int(fd), unix.IPPROTO_TCP, unix.TCP_CONGESTION, opts.CC); err != nil {
+					log.Printf("failed to set TCP_CONGESTION to %q: %v", opts.CC, err)
+				}
+			}
 		})
 		if err != nil {
 			log.Printf("failed to control raw connection: %v", err)
@@ -80,8 +137,15 @@ func RunTCPServer(addr string, data []byte) error {
 			continue
 		}
 
+		// netemCfgが指定されている場合、TLSハンドシェイクを含む以降の通信すべてに
+		// ネットワーク劣化条件を注入する
+		var transportConn net.Conn = rawConn
+		if opts.NetemCfg != nil {
+			transportConn = netem.NewConn(rawConn, *opts.NetemCfg)
+		}
+
 		// TLSハンドシェイク
-		conn := tls.Server(rawConn, tlsConfig)
+		conn := tls.Server(transportConn, tlsConfig)
 
 		log.Printf("Accepted TCP connection from %s", conn.RemoteAddr())
 
@@ -89,17 +153,42 @@ func RunTCPServer(addr string, data []byte) error {
 		go func(c net.Conn) {
 			// 関数が返るときに接続をクローズします。
 			defer c.Close()
-			// データをクライアントに書き込みます。
-			if _, err := c.Write(data); err != nil {
+
+			// TLSハンドシェイクを明示的に行い、/metricsに出す所要時間を計測します。
+			var handshakeDuration time.Duration
+			if tlsConn, ok := c.(*tls.Conn); ok {
+				handshakeStart := time.Now()
+				if err := tlsConn.Handshake(); err != nil {
+					log.Printf("TLS handshake failed: %v", err)
+					return
+				}
+				handshakeDuration = time.Since(handshakeStart)
+			}
+
+			// ペイロードをクライアントにストリーミングで書き込みます。
+			dataTransferStart := time.Now()
+			n, err := io.Copy(c, source.Reader())
+			if err != nil {
 				log.Printf("failed to write data to client: %v", err)
+				return
 			}
+			dataTransferDuration := time.Since(dataTransferStart)
+
+			var throughputGbps float64
+			if s := dataTransferDuration.Seconds(); s > 0 {
+				throughputGbps = (float64(n) * 8) / (s * 1e9)
+			}
+			Metrics.Observe(n, handshakeDuration, dataTransferDuration, throughputGbps)
 		}(conn)
 	}
 }
 
 // RunQUICServer は、指定されたアドレスでQUICサーバーを開始します。接続してきたクライアントに
-// 提供されたデータを送信します。
-func RunQUICServer(addr string, data []byte) error {
+// sourceの内容をストリーミングで送信します（RunTCPServerと同様、各接続ごとにsource.Reader()で
+// 新しいReaderを取得する）。opts.ZeroRTTがtrueの場合、クライアントからの0-RTT早期データの
+// 受け入れ（Allow0RTT）を有効にします。opts.NetemCfgが非nilの場合、quic.Transportに差し込んだ
+// netem.PacketConn経由でリッスンし、RTT・帯域等のネットワーク劣化条件を注入します。
+func RunQUICServer(addr string, source data.Source, opts RunOptions) error {
 	// pprofサーバーを別のゴルーチンで開始し、ブロッキングしないようにします。
 	go pprofServer()
 
@@ -116,10 +205,26 @@ func RunQUICServer(addr string, data []byte) error {
 	quicConfig := &quic.Config{
 		DisablePathMTUDiscovery: true,
 		MaxIdleTimeout:          time.Minute,
+		Allow0RTT:               opts.ZeroRTT,
 	}
 
-	// QUIC接続をリッスンします。
-	l, err := quic.ListenAddr(addr, tlsConfig, quicConfig)
+	// QUIC接続をリッスンします。netemCfgが指定されている場合はnetem.PacketConnを
+	// quic.Transportに差し込んでリッスンすることで、ネットワーク劣化条件を注入します。
+	var l *quic.Listener
+	if opts.NetemCfg != nil {
+		udpAddr, resolveErr := net.ResolveUDPAddr("udp", addr)
+		if resolveErr != nil {
+			return fmt.Errorf("failed to resolve address %s: %w", addr, resolveErr)
+		}
+		udpConn, listenErr := net.ListenUDP("udp", udpAddr)
+		if listenErr != nil {
+			return fmt.Errorf("failed to listen on %s: %w", addr, listenErr)
+		}
+		tr := &quic.Transport{Conn: netem.NewPacketConn(udpConn, *opts.NetemCfg)}
+		l, err = tr.Listen(tlsConfig, quicConfig)
+	} else {
+		l, err = quic.ListenAddr(addr, tlsConfig, quicConfig)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to listen on %s: %w", addr, err)
 	}
@@ -147,9 +252,220 @@ func RunQUICServer(addr string, data []byte) error {
 			// 関数が返るときにストリームをクローズします。
 			defer stream.Close()
 
-			// データをクライアントに書き込みます。
-			if _, err := stream.Write(data); err != nil {
+			// ペイロードをクライアントにストリーミングで書き込みます。Acceptの時点で
+			// 既にハンドシェイクは完了しているため、/metricsにはハンドシェイク時間は
+			// 計上しない。
+			dataTransferStart := time.Now()
+			n, err := io.Copy(stream, source.Reader())
+			if err != nil {
 				log.Printf("failed to write data to client: %v", err)
+				return
+			}
+			dataTransferDuration := time.Since(dataTransferStart)
+
+			var throughputGbps float64
+			if s := dataTransferDuration.Seconds(); s > 0 {
+				throughputGbps = (float64(n) * 8) / (s * 1e9)
+			}
+			Metrics.Observe(n, 0, dataTransferDuration, throughputGbps)
+		}(conn)
+	}
+}
+
+// RunHTTP3Server は、指定されたアドレスでHTTP/3サーバーを開始します。受信したGETリクエストに対し、
+// sourceの内容をレスポンスボディとしてストリーミングします。RunTCPServer/RunQUICServerと同様、
+// リクエストごとにsource.Reader()で新しいReaderを取得するため、巨大なペイロードでも1リクエスト
+// ぶんをメモリに確保せずに配信できます。
+func RunHTTP3Server(addr string, source data.Source) error {
+	// pprofサーバーを別のゴルーチンで開始し、ブロッキングしないようにします。
+	go pprofServer()
+
+	// TLS設定を取得（自己署名証明書）
+	tlsConfig, err := tlsutil.Setup()
+	if err != nil {
+		return fmt.Errorf("failed to setup TLS: %w", err)
+	}
+
+	// QUICの設定（RunQUICServerと同様、Path MTU Discoveryを無効化）
+	quicConfig := &quic.Config{
+		DisablePathMTUDiscovery: true,
+		MaxIdleTimeout:          time.Minute,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.Copy(w, source.Reader()); err != nil {
+			log.Printf("failed to write data to client: %v", err)
+		}
+	})
+
+	h3Server := &http3.Server{
+		Addr:       addr,
+		Handler:    mux,
+		TLSConfig:  tlsConfig,
+		QUICConfig: quicConfig,
+	}
+	defer h3Server.Close()
+
+	log.Printf("HTTP/3 server listening on %s", addr)
+	if err := h3Server.ListenAndServe(); err != nil {
+		return fmt.Errorf("failed to start HTTP/3 server: %w", err)
+	}
+	return nil
+}
+
+// RunTCPServerRR は、指定されたアドレスでrrワークロード用のTCPサーバーを開始します。接続してきた
+// クライアントから固定サイズのリクエストを受信するたびに、同じ内容をそのままエコーバックします。
+func RunTCPServerRR(addr string) error {
+	// pprofサーバーを別のゴルーチンで開始し、ブロッキングしないようにします。
+	go pprofServer()
+
+	// TLS設定を取得（自己署名証明書）
+	tlsConfig, err := tlsutil.Setup()
+	if err != nil {
+		return fmt.Errorf("failed to setup TLS: %w", err)
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	defer l.Close()
+	log.Printf("TCP rr server listening on %s", addr)
+
+	for {
+		rawConn, err := l.Accept()
+		if err != nil {
+			log.Printf("failed to accept connection: %v", err)
+			continue
+		}
+
+		conn := tls.Server(rawConn, tlsConfig)
+		log.Printf("Accepted TCP rr connection from %s", conn.RemoteAddr())
+
+		go func(c net.Conn) {
+			defer c.Close()
+			buf := make([]byte, rrPayloadSize)
+			for {
+				if _, err := io.ReadFull(c, buf); err != nil {
+					if err != io.EOF {
+						log.Printf("failed to read rr request: %v", err)
+					}
+					return
+				}
+				if _, err := c.Write(buf); err != nil {
+					log.Printf("failed to echo rr response: %v", err)
+					return
+				}
+			}
+		}(conn)
+	}
+}
+
+// RunQUICServerRR は、指定されたアドレスでrrワークロード用のQUICサーバーを開始します。クライアント
+// が開いたストリーム上で受信した固定サイズのリクエストを、そのままエコーバックします。
+func RunQUICServerRR(addr string) error {
+	// pprofサーバーを別のゴルーチンで開始し、ブロッキングしないようにします。
+	go pprofServer()
+
+	// QUICのためのTLS設定をセットアップします。
+	tlsConfig, err := tlsutil.Setup()
+	if err != nil {
+		return fmt.Errorf("failed to setup TLS: %w", err)
+	}
+
+	quicConfig := &quic.Config{
+		DisablePathMTUDiscovery: true,
+		MaxIdleTimeout:          time.Minute,
+	}
+
+	l, err := quic.ListenAddr(addr, tlsConfig, quicConfig)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	defer l.Close()
+	log.Printf("QUIC rr server listening on %s", addr)
+
+	for {
+		conn, err := l.Accept(context.Background())
+		if err != nil {
+			log.Printf("failed to accept connection: %v", err)
+			continue
+		}
+		log.Printf("Accepted QUIC rr connection from %s", conn.RemoteAddr())
+
+		go func(c *quic.Conn) {
+			stream, err := c.AcceptStream(context.Background())
+			if err != nil {
+				log.Printf("failed to accept stream: %v", err)
+				return
+			}
+			defer stream.Close()
+
+			buf := make([]byte, rrPayloadSize)
+			for {
+				if _, err := io.ReadFull(stream, buf); err != nil {
+					if err != io.EOF {
+						log.Printf("failed to read rr request: %v", err)
+					}
+					return
+				}
+				if _, err := stream.Write(buf); err != nil {
+					log.Printf("failed to echo rr response: %v", err)
+					return
+				}
+			}
+		}(conn)
+	}
+}
+
+// RunQUICServerStreams は、指定されたアドレスでstreamsワークロード用のQUICサーバーを開始します。
+// 1つの接続の上にnumStreams本のストリームを並行に開き、それぞれでsourceの内容を送信します。
+// 他のRun*Server関数と同様、ストリームごとにsource.Reader()で新しいReaderを取得します。
+func RunQUICServerStreams(addr string, source data.Source, numStreams int) error {
+	// pprofサーバーを別のゴルーチンで開始し、ブロッキングしないようにします。
+	go pprofServer()
+
+	// QUICのためのTLS設定をセットアップします。
+	tlsConfig, err := tlsutil.Setup()
+	if err != nil {
+		return fmt.Errorf("failed to setup TLS: %w", err)
+	}
+
+	quicConfig := &quic.Config{
+		DisablePathMTUDiscovery: true,
+		MaxIdleTimeout:          time.Minute,
+	}
+
+	l, err := quic.ListenAddr(addr, tlsConfig, quicConfig)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	defer l.Close()
+	log.Printf("QUIC streams server listening on %s", addr)
+
+	for {
+		conn, err := l.Accept(context.Background())
+		if err != nil {
+			log.Printf("failed to accept connection: %v", err)
+			continue
+		}
+		log.Printf("Accepted QUIC connection from %s", conn.RemoteAddr())
+
+		go func(c *quic.Conn) {
+			for i := 0; i < numStreams; i++ {
+				go func() {
+					stream, err := c.OpenStreamSync(context.Background())
+					if err != nil {
+						log.Printf("failed to open stream: %v", err)
+						return
+					}
+					defer stream.Close()
+
+					if _, err := io.Copy(stream, source.Reader()); err != nil {
+						log.Printf("failed to write data to client: %v", err)
+					}
+				}()
 			}
 		}(conn)
 	}
@@ -0,0 +1,21 @@
+// stats パッケージは、レイテンシ計測などで共通して使うパーセンタイル計算を提供します。
+package stats
+
+import (
+	"math"
+	"time"
+)
+
+// Percentile は、昇順ソート済みのsortedから、指定パーセンタイル（0-100）の値を取り出す。
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	} else if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}